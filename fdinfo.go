@@ -0,0 +1,157 @@
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// FDInfo describes a single file descriptor passed to us by systemd, as
+// reported by the kernel via getsockopt(2) and getsockname(2) -- similar to
+// what sd_is_socket(3) and friends let C programs check.
+type FDInfo struct {
+	// Name is the "FileDescriptorName=" this descriptor was tagged with
+	// ("" if none was set).
+	Name string
+
+	// Fd is the file descriptor number.
+	Fd int
+
+	// Family is the socket address family, e.g. syscall.AF_INET,
+	// syscall.AF_INET6, or syscall.AF_UNIX.
+	Family int
+
+	// SockType is the socket type, e.g. syscall.SOCK_STREAM or
+	// syscall.SOCK_DGRAM.
+	SockType int
+
+	// Protocol is the socket protocol, e.g. syscall.IPPROTO_TCP.
+	Protocol int
+
+	// Addr is the local address of the socket, as reported by
+	// getsockname(2). It is nil if the descriptor is not a socket, or the
+	// kernel could not report an address for it.
+	Addr net.Addr
+}
+
+// Describe returns introspection information for each file descriptor
+// passed to us by systemd, keyed by name the same way Files does.
+//
+// Unlike Listeners and PacketConns, which only hold the file descriptors
+// that could be turned into the corresponding Go type (and return an error
+// if some descriptor could be turned into neither), Describe reports on
+// every descriptor we received, which is useful to validate that the unit
+// file matches what the program expects.
+func Describe() (map[string][]FDInfo, error) {
+	parse()
+	if parseError != nil {
+		return nil, parseError
+	}
+
+	infos := map[string][]FDInfo{}
+	for name, fs := range files {
+		for _, f := range fs {
+			info, err := describeFD(name, int(f.Fd()))
+			if err != nil {
+				return nil, err
+			}
+			infos[name] = append(infos[name], info)
+		}
+	}
+
+	return infos, nil
+}
+
+// describeFD builds the FDInfo for a single file descriptor.
+func describeFD(name string, fd int) (FDInfo, error) {
+	info := FDInfo{Name: name, Fd: fd}
+
+	var err error
+	info.Family, err = syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_DOMAIN)
+	if err == syscall.ENOTSOCK {
+		// Not a socket (e.g. a FIFO or a regular file): report it with only
+		// Name and Fd set.
+		info.Family = 0
+		return info, nil
+	}
+	if err != nil {
+		return info, fmt.Errorf("error getting family of fd %d: %v", fd, err)
+	}
+	info.SockType, err = syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_TYPE)
+	if err != nil {
+		return info, fmt.Errorf("error getting type of fd %d: %v", fd, err)
+	}
+	info.Protocol, err = syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_PROTOCOL)
+	if err != nil {
+		return info, fmt.Errorf("error getting protocol of fd %d: %v", fd, err)
+	}
+
+	if sa, saErr := syscall.Getsockname(fd); saErr == nil {
+		info.Addr = sockaddrToAddr(sa, info.SockType)
+	}
+
+	return info, nil
+}
+
+// sockaddrToAddr converts a syscall.Sockaddr, as returned by getsockname(2),
+// into the closest matching net.Addr.
+func sockaddrToAddr(sa syscall.Sockaddr, sockType int) net.Addr {
+	isDgram := sockType == syscall.SOCK_DGRAM
+
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		if isDgram {
+			return &net.UDPAddr{IP: net.IP(sa.Addr[:]), Port: sa.Port}
+		}
+		return &net.TCPAddr{IP: net.IP(sa.Addr[:]), Port: sa.Port}
+	case *syscall.SockaddrInet6:
+		if isDgram {
+			return &net.UDPAddr{IP: net.IP(sa.Addr[:]), Port: sa.Port}
+		}
+		return &net.TCPAddr{IP: net.IP(sa.Addr[:]), Port: sa.Port}
+	case *syscall.SockaddrUnix:
+		network := "unix"
+		if isDgram {
+			network = "unixgram"
+		}
+		return &net.UnixAddr{Name: sa.Name, Net: network}
+	default:
+		return nil
+	}
+}
+
+// IsSocketInet returns whether the first systemd socket with the given name
+// is an IPv4 or IPv6 socket, optionally also checking that it is bound to
+// the given port (pass 0 to skip that check).
+//
+// This mirrors what sd_is_socket_inet(3) offers C programs, letting callers
+// assert the unit file matches what they expect before they start serving.
+func IsSocketInet(name string, port int) (bool, error) {
+	infos, err := Describe()
+	if err != nil {
+		return false, err
+	}
+
+	fis := infos[name]
+	if len(fis) < 1 {
+		return false, nil
+	}
+
+	fi := fis[0]
+	if fi.Family != syscall.AF_INET && fi.Family != syscall.AF_INET6 {
+		return false, nil
+	}
+
+	if port == 0 {
+		return true, nil
+	}
+
+	switch addr := fi.Addr.(type) {
+	case *net.TCPAddr:
+		return addr.Port == port, nil
+	case *net.UDPAddr:
+		return addr.Port == port, nil
+	default:
+		return false, nil
+	}
+}
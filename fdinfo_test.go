@@ -0,0 +1,82 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestDescribeOneSocket(t *testing.T) {
+	l := newListener(t)
+	defer l.Close()
+	firstFD = listenerFd(t, l)
+
+	setenv(strconv.Itoa(os.Getpid()), "1", "name")
+
+	infos, err := Describe()
+	if err != nil || len(infos) != 1 {
+		t.Fatalf("Got an invalid result: %v // %v", infos, err)
+	}
+
+	fi := infos["name"][0]
+	if fi.Fd != firstFD {
+		t.Errorf("unexpected fd: got %d, want %d", fi.Fd, firstFD)
+	}
+	if fi.SockType != syscall.SOCK_STREAM {
+		t.Errorf("unexpected sock type: got %d, want %d", fi.SockType, syscall.SOCK_STREAM)
+	}
+	if fi.Family != syscall.AF_INET && fi.Family != syscall.AF_INET6 {
+		t.Errorf("unexpected family: %d", fi.Family)
+	}
+
+	port := l.Addr().(*net.TCPAddr).Port
+	ok, err := IsSocketInet("name", port)
+	if err != nil || !ok {
+		t.Errorf("IsSocketInet(name, %d) = %v, %v, want true, nil", port, ok, err)
+	}
+
+	ok, err = IsSocketInet("name", port+1)
+	if err != nil || ok {
+		t.Errorf("IsSocketInet(name, %d) = %v, %v, want false, nil", port+1, ok, err)
+	}
+
+	ok, err = IsSocketInet("nothing", 0)
+	if err != nil || ok {
+		t.Errorf("IsSocketInet(nothing, 0) = %v, %v, want false, nil", ok, err)
+	}
+}
+
+func TestDescribeOneDatagramSocket(t *testing.T) {
+	pc := newPacketConn(t)
+	defer pc.Close()
+	firstFD = packetConnFd(t, pc)
+
+	setenv(strconv.Itoa(os.Getpid()), "1", "name")
+
+	infos, err := Describe()
+	if err != nil || len(infos) != 1 {
+		t.Fatalf("Got an invalid result: %v // %v", infos, err)
+	}
+
+	fi := infos["name"][0]
+	if fi.SockType != syscall.SOCK_DGRAM {
+		t.Errorf("unexpected sock type: got %d, want %d", fi.SockType, syscall.SOCK_DGRAM)
+	}
+
+	if _, ok := fi.Addr.(*net.UDPAddr); !ok {
+		t.Errorf("unexpected addr type: got %T, want *net.UDPAddr", fi.Addr)
+	}
+
+	port := pc.LocalAddr().(*net.UDPAddr).Port
+	ok, err := IsSocketInet("name", port)
+	if err != nil || !ok {
+		t.Errorf("IsSocketInet(name, %d) = %v, %v, want true, nil", port, ok, err)
+	}
+
+	ok, err = IsSocketInet("name", port+1)
+	if err != nil || ok {
+		t.Errorf("IsSocketInet(name, %d) = %v, %v, want false, nil", port+1, ok, err)
+	}
+}
@@ -0,0 +1,167 @@
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// registerMutex guards registered.
+var registerMutex sync.Mutex
+
+// registered holds listeners that callers created themselves (as opposed to
+// the ones we got from systemd), keyed by name using the same convention as
+// FileDescriptorName. They are included in the next StartChild call, in
+// addition to whatever we inherited from systemd on our own startup.
+var registered = map[string][]net.Listener{}
+
+// Register adds l to the set of listeners that will be passed down to a
+// child process on the next StartChild call, under the given name.
+//
+// This is meant for listeners created with net.Listen rather than obtained
+// from systemd: without registering them, a restart via StartChild would
+// silently drop them and the child would have to bind them anew, causing a
+// disruption that graceful restart is meant to avoid.
+//
+// Listeners obtained from systemd (via Listeners or OneListener) do not need
+// to be registered, as StartChild passes them along automatically.
+func Register(name string, l net.Listener) {
+	registerMutex.Lock()
+	defer registerMutex.Unlock()
+	registered[name] = append(registered[name], l)
+}
+
+// fileProvider is implemented by the net types that can hand us a dup of
+// their underlying file descriptor (TCP and Unix listeners, UDP and
+// unixgram packet connections), which are the only ones that make sense to
+// pass across a re-exec.
+type fileProvider interface {
+	File() (*os.File, error)
+}
+
+// fileOf extracts the underlying *os.File from a net.Listener or
+// net.PacketConn value. The returned file is a dup of the original
+// descriptor, and it is the caller's responsibility to close it once done
+// with it.
+func fileOf(v interface{}) (*os.File, error) {
+	fp, ok := v.(fileProvider)
+	if !ok {
+		return nil, fmt.Errorf("value of type %T does not support File()", v)
+	}
+	return fp.File()
+}
+
+// StartChild starts the program argv[0] with arguments argv[1:] (for a
+// restart, the path of the current binary), handing it the
+// listeners and packet connections we got from systemd plus the listeners
+// registered via Register (and any extraFiles), using the same environment
+// variable convention systemd itself uses (LISTEN_PID, LISTEN_FDS,
+// LISTEN_FDNAMES). This lets the child pick them up via the usual
+// Listeners/PacketConns/OneListener/OnePacketConn/Files functions, with no
+// special-casing needed on its side.
+//
+// extraFiles, if not nil, lets the caller pass down additional named files
+// that aren't net.Listeners or net.PacketConns.
+//
+// Listeners registered via Register are the only ones carried over beyond
+// what systemd gave us; a packet connection created with net.ListenPacket
+// rather than inherited from systemd has no equivalent registration point
+// yet, and must be handed over via extraFiles if it needs to survive the
+// restart.
+//
+// The child inherits our environment, working directory, and standard file
+// descriptors. It is the caller's responsibility to wait for the child to
+// signal readiness (for example, on its own socket) before shutting down.
+func StartChild(argv []string, extraFiles map[string]*os.File) (*os.Process, error) {
+	parse()
+	if parseError != nil {
+		return nil, parseError
+	}
+
+	registerMutex.Lock()
+	defer registerMutex.Unlock()
+
+	names := []string{}
+	files := []*os.File{}
+
+	// dupedFiles holds the subset of files that File() duped on our behalf
+	// (everything except extraFiles, which the caller owns); we must close
+	// them once the child has them, or they leak.
+	dupedFiles := []*os.File{}
+	defer func() {
+		for _, f := range dupedFiles {
+			f.Close()
+		}
+	}()
+
+	addFile := func(name string, v interface{}) error {
+		f, err := fileOf(v)
+		if err != nil {
+			return fmt.Errorf("error getting file for %q: %v", name, err)
+		}
+		names = append(names, name)
+		files = append(files, f)
+		dupedFiles = append(dupedFiles, f)
+		return nil
+	}
+
+	for name, ls := range listeners {
+		for _, l := range ls {
+			if err := addFile(name, l); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for name, pcs := range packetConns {
+		for _, pc := range pcs {
+			if err := addFile(name, pc); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for name, ls := range registered {
+		for _, l := range ls {
+			if err := addFile(name, l); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for name, f := range extraFiles {
+		names = append(names, name)
+		files = append(files, f)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("error getting working directory: %v", err)
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Dir = wd
+	cmd.ExtraFiles = files
+
+	// LISTEN_PID should be the child's pid, but we have no way of knowing it
+	// until after the fork+exec (and hence the environment) is already in
+	// place. We use 0 instead, which parse() treats as "trust these
+	// descriptors regardless of pid".
+	cmd.Env = append(os.Environ(),
+		"LISTEN_PID=0",
+		fmt.Sprintf("LISTEN_FDS=%d", len(files)),
+		"LISTEN_FDNAMES="+strings.Join(names, ":"),
+	)
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error starting child: %v", err)
+	}
+
+	return cmd.Process, nil
+}
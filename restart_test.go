@@ -0,0 +1,154 @@
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestMain lets this test binary also act as the child process spawned by
+// StartChild, when invoked with GO_TEST_HELPER_PROCESS=1 in its
+// environment. This mirrors the "helper process" pattern used by the
+// os/exec tests in the standard library.
+func TestMain(m *testing.M) {
+	if os.Getenv("GO_TEST_HELPER_PROCESS") == "1" {
+		helperProcessMain()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// helperProcessMain runs as the re-exec'd child. If it was handed a "child"
+// listener, it accepts one connection and writes a line to it. If it was
+// handed a "dgram" packet connection, it reads one packet and replies with
+// "pong". Either way, it exits once done.
+func helperProcessMain() {
+	if pc, err := OnePacketConn("dgram"); err == nil && pc != nil {
+		buf := make([]byte, 64)
+		_, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "helper: ReadFrom failed: %v\n", err)
+			os.Exit(1)
+		}
+		pc.WriteTo([]byte("pong"), addr)
+		return
+	}
+
+	l, err := OneListener("child")
+	if err != nil || l == nil {
+		fmt.Fprintf(os.Stderr, "helper: OneListener failed: %v (l=%v)\n", err, l)
+		os.Exit(1)
+	}
+
+	conn, err := l.Accept()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "helper: Accept failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintln(conn, "hello from child")
+	conn.Close()
+}
+
+func TestStartChild(t *testing.T) {
+	l := newListener(t)
+	defer l.Close()
+
+	Register("child", l)
+	defer delete(registered, "child")
+
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatalf("could not find our own executable: %v", err)
+	}
+
+	os.Setenv("GO_TEST_HELPER_PROCESS", "1")
+	defer os.Unsetenv("GO_TEST_HELPER_PROCESS")
+
+	child, err := StartChild([]string{self}, nil)
+	if err != nil {
+		t.Fatalf("StartChild failed: %v", err)
+	}
+	defer child.Kill()
+
+	conn, err := net.DialTimeout("tcp", l.Addr().String(), 2*time.Second)
+	if err != nil {
+		t.Fatalf("could not dial child: %v", err)
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("could not read from child: %v", err)
+	}
+
+	got := string(buf[:n])
+	want := "hello from child\n"
+	if got != want {
+		t.Errorf("unexpected reply from child: got %q, want %q", got, want)
+	}
+
+	state, err := child.Wait()
+	if err != nil {
+		t.Fatalf("child did not exit cleanly: %v", err)
+	}
+	if !state.Success() {
+		t.Errorf("child exited with error: %v", state)
+	}
+}
+
+func TestStartChildForwardsPacketConns(t *testing.T) {
+	pc := newPacketConn(t)
+	defer pc.Close()
+	firstFD = packetConnFd(t, pc)
+	setenv(strconv.Itoa(os.Getpid()), "1", "dgram")
+
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatalf("could not find our own executable: %v", err)
+	}
+
+	os.Setenv("GO_TEST_HELPER_PROCESS", "1")
+	defer os.Unsetenv("GO_TEST_HELPER_PROCESS")
+
+	child, err := StartChild([]string{self}, nil)
+	if err != nil {
+		t.Fatalf("StartChild failed: %v", err)
+	}
+	defer child.Kill()
+
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("could not dial child: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("could not write to child: %v", err)
+	}
+
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("could not read from child: %v", err)
+	}
+
+	got := string(buf[:n])
+	want := "pong"
+	if got != want {
+		t.Errorf("unexpected reply from child: got %q, want %q", got, want)
+	}
+
+	state, err := child.Wait()
+	if err != nil {
+		t.Fatalf("child did not exit cleanly: %v", err)
+	}
+	if !state.Success() {
+		t.Errorf("child exited with error: %v", state)
+	}
+}
@@ -26,6 +26,7 @@ var (
 // which can be problematic (see parse).
 var files map[string][]*os.File
 var listeners map[string][]net.Listener
+var packetConns map[string][]net.PacketConn
 var parseError error
 var listenError error
 var mutex sync.Mutex
@@ -58,7 +59,12 @@ func parse() {
 		parseError = fmt.Errorf(
 			"error converting $LISTEN_PID=%q: %v", pidStr, err)
 		return
-	} else if pid != os.Getpid() {
+	} else if pid != os.Getpid() && pid != 0 {
+		// 0 is not a valid pid for a real process, so we use it as a
+		// sentinel: StartChild can't know its child's pid until after the
+		// fork+exec has already happened (and the environment has been set),
+		// so it sets LISTEN_PID=0 to mean "these descriptors are ours,
+		// regardless of pid".
 		parseError = ErrPIDMismatch
 		return
 	}
@@ -87,6 +93,7 @@ func parse() {
 
 	files = map[string][]*os.File{}
 	listeners = map[string][]net.Listener{}
+	packetConns = map[string][]net.PacketConn{}
 
 	for i := 0; i < nfds; i++ {
 		fd := firstFD + i
@@ -99,14 +106,23 @@ func parse() {
 		f := os.NewFile(uintptr(fd), sysName)
 		files[name] = append(files[name], f)
 
-		// Note this can fail for non-TCP listeners, so we put the error in a
+		// Try to make a stream listener out of it first; if that fails, it
+		// may be a datagram socket (e.g. UDP or unixgram), so fall back to
+		// making a packet connection instead. Note both of these can fail
+		// for other kinds of file descriptors, so we put the error in a
 		// separate variable.
 		lis, err := net.FileListener(f)
-		if err != nil {
+		if err == nil {
+			listeners[name] = append(listeners[name], lis)
+			continue
+		}
+
+		pc, pcErr := net.FilePacketConn(f)
+		if pcErr == nil {
+			packetConns[name] = append(packetConns[name], pc)
+		} else {
 			listenError = fmt.Errorf(
 				"Error making listener out of fd %d: %v", fd, err)
-		} else {
-			listeners[name] = append(listeners[name], lis)
 		}
 	}
 
@@ -169,6 +185,54 @@ func OneListener(name string) (net.Listener, error) {
 	return lis[0], nil
 }
 
+// PacketConns returns net.PacketConns corresponding to the datagram file
+// descriptors passed by systemd via environment variables (for example,
+// UDP or unixgram sockets).
+//
+// It returns a map of the form (file descriptor name -> []net.PacketConn).
+//
+// The file descriptor name comes from the "FileDescriptorName=" option in the
+// systemd socket unit. Multiple socket units can have the same name, hence
+// the slice of packet connections for each name.
+//
+// This is analogous to Listeners, but for datagram sockets: a given file
+// descriptor ends up in exactly one of the two maps, never both.
+//
+// See sd_listen_fds(3) and sd_listen_fds_with_names(3) for more details on
+// how the passing works.
+func PacketConns() (map[string][]net.PacketConn, error) {
+	parse()
+	if parseError != nil {
+		return packetConns, parseError
+	}
+	return packetConns, listenError
+}
+
+// OnePacketConn returns a net.PacketConn for the first systemd socket with
+// the given name. If there are none, the connection and error will both be
+// nil. An error will be returned only if there were issues parsing the file
+// descriptors.
+//
+// This function can be convenient for simple callers where you know there's
+// only one file descriptor being passed with the given name.
+//
+// This is a convenience function built on top of PacketConns().
+func OnePacketConn(name string) (net.PacketConn, error) {
+	parse()
+	if parseError != nil {
+		return nil, parseError
+	}
+	if listenError != nil {
+		return nil, listenError
+	}
+
+	pc := packetConns[name]
+	if len(pc) < 1 {
+		return nil, nil
+	}
+	return pc[0], nil
+}
+
 // Listen returns a net.Listener for the given address, similar to net.Listen.
 //
 // If the address begins with "&" it is interpreted as a systemd socket being
@@ -196,6 +260,31 @@ func Listen(netw, laddr string) (net.Listener, error) {
 	}
 }
 
+// ListenPacket returns a net.PacketConn for the given address, similar to
+// net.ListenPacket.
+//
+// If the address begins with "&" it is interpreted as a systemd socket being
+// passed, the same way Listen does. This is meant for datagram sockets (for
+// example "udp" or "unixgram"), where the underlying systemd file descriptor
+// is a SOCK_DGRAM socket rather than a listening one.
+//
+// Otherwise, it uses net.ListenPacket to create a new packet connection with
+// the given net and local address.
+//
+// This is a convenience function built on top of PacketConns().
+func ListenPacket(netw, laddr string) (net.PacketConn, error) {
+	if strings.HasPrefix(laddr, "&") {
+		name := laddr[1:]
+		pc, err := OnePacketConn(name)
+		if pc == nil && err == nil {
+			err = fmt.Errorf("systemd socket %q not found", name)
+		}
+		return pc, err
+	} else {
+		return net.ListenPacket(netw, laddr)
+	}
+}
+
 // Files returns the open files passed by systemd via environment variables.
 //
 // It returns a map of the form (file descriptor name -> []*os.File).
@@ -48,6 +48,33 @@ func listenerFd(t *testing.T, l *net.TCPListener) int {
 	return int(f.Fd())
 }
 
+// newPacketConn creates a UDP packet connection.
+func newPacketConn(t *testing.T) *net.UDPConn {
+	t.Helper()
+	addr := &net.UDPAddr{
+		Port: 0,
+	}
+
+	pc, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		t.Fatalf("Could not create UDP packet conn: %v", err)
+	}
+
+	return pc
+}
+
+// packetConnFd returns a file descriptor for the packet connection.
+// Note it is a NEW file descriptor, not the original one.
+func packetConnFd(t *testing.T, pc *net.UDPConn) int {
+	t.Helper()
+	f, err := pc.File()
+	if err != nil {
+		t.Fatalf("Could not get UDP packet conn file: %v", err)
+	}
+
+	return int(f.Fd())
+}
+
 func sameAddr(a, b net.Addr) bool {
 	return a.Network() == b.Network() && a.String() == b.String()
 }
@@ -334,6 +361,67 @@ func TestManySockets(t *testing.T) {
 
 }
 
+func TestOneDatagramSocket(t *testing.T) {
+	pc := newPacketConn(t)
+	defer pc.Close()
+	firstFD = packetConnFd(t, pc)
+
+	setenv(strconv.Itoa(os.Getpid()), "1", "name")
+
+	{
+		pcMap, err := PacketConns()
+		if err != nil || len(pcMap) != 1 {
+			t.Fatalf("Got an invalid result: %v // %v", pcMap, err)
+		}
+
+		pcs := pcMap["name"]
+		if !sameAddr(pcs[0].LocalAddr(), pc.LocalAddr()) {
+			t.Errorf("PacketConn 0 address mismatch, expected %#v, got %#v",
+				pc.LocalAddr(), pcs[0].LocalAddr())
+		}
+
+		onePC, err := OnePacketConn("name")
+		if err != nil {
+			t.Errorf("OnePacketConn error: %v", err)
+		}
+		if !sameAddr(onePC.LocalAddr(), pc.LocalAddr()) {
+			t.Errorf("OnePacketConn address mismatch, expected %#v, got %#v",
+				pc.LocalAddr(), onePC.LocalAddr())
+		}
+	}
+
+	// A datagram socket should not show up as a listener.
+	if lsMap, err := Listeners(); err != nil || len(lsMap) != 0 {
+		t.Errorf("Got an unexpected listener: %v // %v", lsMap, err)
+	}
+
+	if os.Getenv("LISTEN_PID") != "" || os.Getenv("LISTEN_FDS") != "" {
+		t.Errorf("Failed to reset the environment")
+	}
+}
+
+func TestListenPacket(t *testing.T) {
+	orig := newPacketConn(t)
+	defer orig.Close()
+	firstFD = packetConnFd(t, orig)
+	setenv(strconv.Itoa(os.Getpid()), "1", "name")
+
+	pc, err := ListenPacket("udp", "&name")
+	if err != nil {
+		t.Errorf("ListenPacket failed: %v", err)
+	}
+	if !sameAddr(pc.LocalAddr(), orig.LocalAddr()) {
+		t.Errorf("PacketConn address mismatch, expected %#v, got %#v",
+			orig.LocalAddr(), pc.LocalAddr())
+	}
+
+	pc, err = ListenPacket("udp", ":0")
+	if err != nil {
+		t.Errorf("ListenPacket failed: %v", err)
+	}
+	t.Logf("packet conn created at %v", pc.LocalAddr())
+}
+
 func TestListen(t *testing.T) {
 	orig := newListener(t)
 	defer orig.Close()
@@ -0,0 +1,84 @@
+package systemd
+
+import (
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// UnixSocketConfig configures how ListenUnix creates a new unix socket, for
+// the case where systemd didn't pass one down to us.
+type UnixSocketConfig struct {
+	// Addr is the listen address, following the same convention as Listen:
+	// if it begins with "&" it names a systemd socket (for example
+	// "&http"); otherwise it is the filesystem path to bind.
+	Addr string
+
+	// SocketMode is the permission mode to set on the socket file after
+	// binding it. It is ignored when the listener comes from systemd,
+	// since systemd is then responsible for the socket's permissions.
+	SocketMode fs.FileMode
+
+	// RemoveExisting, if true, removes a stale socket file left behind at
+	// Addr (for example, by a previous unclean exit) before binding.
+	RemoveExisting bool
+}
+
+// ListenUnix returns a net.Listener for a unix socket, honouring cfg.Addr
+// the same way Listen honours laddr: if it begins with "&" it is a systemd
+// socket, otherwise a new one is created with net.Listen("unix", ...),
+// applying cfg.SocketMode and, if requested, removing a stale socket file
+// first.
+//
+// This is meant for callers that want a single address string to mean
+// "systemd fd, or a properly-permissioned unix socket", without having to
+// re-implement the chmod/unlink dance themselves.
+//
+// This is a convenience function built on top of Listen.
+func ListenUnix(cfg UnixSocketConfig) (net.Listener, error) {
+	if strings.HasPrefix(cfg.Addr, "&") {
+		return Listen("unix", cfg.Addr)
+	}
+
+	if cfg.RemoveExisting {
+		fi, err := os.Lstat(cfg.Addr)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf(
+				"error checking existing socket %q: %v", cfg.Addr, err)
+		}
+		if err == nil {
+			if fi.Mode()&fs.ModeSocket == 0 {
+				return nil, fmt.Errorf(
+					"refusing to remove %q, it is not a socket", cfg.Addr)
+			}
+			if err := os.Remove(cfg.Addr); err != nil {
+				return nil, fmt.Errorf(
+					"error removing existing socket %q: %v", cfg.Addr, err)
+			}
+		}
+	}
+
+	// Create the socket file with the most restrictive permissions
+	// possible, to close the window between bind(2) and the Chmod below
+	// during which it would otherwise sit at the umask-derived default
+	// (typically world-accessible) and defeat the point of SocketMode.
+	oldMask := syscall.Umask(0o777)
+	lis, err := net.Listen("unix", cfg.Addr)
+	syscall.Umask(oldMask)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.SocketMode != 0 {
+		if err := os.Chmod(cfg.Addr, cfg.SocketMode); err != nil {
+			lis.Close()
+			return nil, fmt.Errorf(
+				"error setting permissions on socket %q: %v", cfg.Addr, err)
+		}
+	}
+
+	return lis, nil
+}
@@ -0,0 +1,101 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestListenUnixFromSystemd(t *testing.T) {
+	l := newListener(t)
+	defer l.Close()
+	firstFD = listenerFd(t, l)
+	setenv(strconv.Itoa(os.Getpid()), "1", "name")
+
+	lis, err := ListenUnix(UnixSocketConfig{Addr: "&name"})
+	if err != nil {
+		t.Fatalf("ListenUnix failed: %v", err)
+	}
+	if !sameAddr(lis.Addr(), l.Addr()) {
+		t.Errorf("address mismatch, expected %#v, got %#v", l.Addr(), lis.Addr())
+	}
+}
+
+func TestListenUnixCreatesSocket(t *testing.T) {
+	setenv("", "")
+
+	path := filepath.Join(t.TempDir(), "test.sock")
+
+	lis, err := ListenUnix(UnixSocketConfig{
+		Addr:       path,
+		SocketMode: 0660,
+	})
+	if err != nil {
+		t.Fatalf("ListenUnix failed: %v", err)
+	}
+	defer lis.Close()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("could not stat socket: %v", err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0660 {
+		t.Errorf("unexpected socket permissions: got %o, want %o", perm, 0660)
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("could not dial socket: %v", err)
+	}
+	conn.Close()
+}
+
+func TestListenUnixRemovesStaleSocket(t *testing.T) {
+	setenv("", "")
+
+	path := filepath.Join(t.TempDir(), "test.sock")
+
+	// Leave a stale socket file behind, simulating an unclean exit: close
+	// the listener without letting it unlink the file first.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("could not create stale socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	if _, err := ListenUnix(UnixSocketConfig{Addr: path}); err == nil {
+		t.Fatalf("expected ListenUnix to fail on a stale socket without RemoveExisting")
+	}
+
+	lis, err := ListenUnix(UnixSocketConfig{
+		Addr:           path,
+		RemoveExisting: true,
+	})
+	if err != nil {
+		t.Fatalf("ListenUnix failed: %v", err)
+	}
+	defer lis.Close()
+}
+
+func TestListenUnixRefusesToRemoveNonSocket(t *testing.T) {
+	setenv("", "")
+
+	path := filepath.Join(t.TempDir(), "test.sock")
+	if err := os.WriteFile(path, []byte("not a socket"), 0644); err != nil {
+		t.Fatalf("could not create regular file: %v", err)
+	}
+
+	if _, err := ListenUnix(UnixSocketConfig{
+		Addr:           path,
+		RemoveExisting: true,
+	}); err == nil {
+		t.Fatalf("expected ListenUnix to refuse to remove a non-socket file")
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("file was removed despite not being a socket: %v", err)
+	}
+}